@@ -0,0 +1,48 @@
+package state
+
+import "testing"
+
+func taskWithIPs(ips ...string) Task {
+	return Task{SlaveIPs: ips}
+}
+
+func TestIPsFiltered(t *testing.T) {
+	task := taskWithIPs("10.0.0.1", "2001:db8::1", "10.0.0.2")
+
+	cases := []struct {
+		name   string
+		family string
+		want   []string
+	}{
+		{"ipv4 only", IPFilterIPv4, []string{"10.0.0.1", "10.0.0.2"}},
+		{"ipv6 only", IPFilterIPv6, []string{"2001:db8::1"}},
+		{"prefer ipv4", IPFilterPreferIPv4, []string{"10.0.0.1", "10.0.0.2", "2001:db8::1"}},
+		{"prefer ipv6", IPFilterPreferIPv6, []string{"2001:db8::1", "10.0.0.1", "10.0.0.2"}},
+		{"unrecognized family", "bogus", []string{"10.0.0.1", "2001:db8::1", "10.0.0.2"}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ips := task.IPsFiltered(c.family, "host")
+			if len(ips) != len(c.want) {
+				t.Fatalf("IPsFiltered(%q) = %v, want %v", c.family, ips, c.want)
+			}
+			for i, ip := range ips {
+				if ip.String() != c.want[i] {
+					t.Errorf("IPsFiltered(%q)[%d] = %s, want %s", c.family, i, ip, c.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestIPFiltered(t *testing.T) {
+	task := taskWithIPs("2001:db8::1", "10.0.0.1")
+
+	if got := task.IPFiltered(IPFilterIPv4, "host"); got != "10.0.0.1" {
+		t.Errorf("IPFiltered(ipv4) = %q, want 10.0.0.1", got)
+	}
+	if got := task.IPFiltered(IPFilterIPv6, "host"); got != "2001:db8::1" {
+		t.Errorf("IPFiltered(ipv6) = %q, want 2001:db8::1", got)
+	}
+}
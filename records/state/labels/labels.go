@@ -0,0 +1,102 @@
+// Package labels parses well-known DiscoveryInfo labels, in the style of
+// how Traefik consumes Marathon/Mesos labels to drive routing policy, and
+// exposes them as typed metadata that record generators can act on instead
+// of every caller re-parsing DiscoveryInfo.Labels.Labels by hand.
+package labels
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mesosphere/mesos-dns/records/state"
+)
+
+const (
+	// WeightLabel sets the SRV record weight for a task's own service.
+	WeightLabel = "mesos-dns.srv.weight"
+	// PriorityLabel sets the SRV record priority for a task's own service.
+	PriorityLabel = "mesos-dns.srv.priority"
+	// AliasLabelPrefix, followed by an index (e.g. "mesos-dns.alias.0"),
+	// adds an extra DNS name the task should also be reachable under.
+	AliasLabelPrefix = "mesos-dns.alias."
+	// SuppressLabel, when set to "true", opts a task out of DNS entirely.
+	SuppressLabel = "mesos-dns.suppress"
+
+	// DefaultWeight is used when WeightLabel is absent or invalid.
+	DefaultWeight uint16 = 1
+	// DefaultPriority is used when PriorityLabel is absent or invalid.
+	DefaultPriority uint16 = 1
+)
+
+// Task wraps a state.Task with the service metadata parsed from its
+// DiscoveryInfo labels.
+type Task struct {
+	state.Task
+	// Weight is the SRV record weight to use for this task.
+	Weight uint16
+	// Priority is the SRV record priority to use for this task.
+	Priority uint16
+	// Aliases are additional DNS names this task should be reachable under,
+	// besides the name mesos-dns derives from the task itself.
+	Aliases []string
+	// Suppressed, when true, means this task should not be published in DNS
+	// at all.
+	Suppressed bool
+}
+
+// NewTask parses t's DiscoveryInfo labels and returns a Task carrying the
+// resulting metadata alongside the embedded state.Task.
+func NewTask(t state.Task) Task {
+	lt := Task{
+		Task:     t,
+		Weight:   DefaultWeight,
+		Priority: DefaultPriority,
+	}
+
+	var aliases []indexedAlias
+	for _, l := range t.DiscoveryInfo.Labels.Labels {
+		switch {
+		case l.Key == WeightLabel:
+			if v, err := parseUint16(l.Value); err == nil {
+				lt.Weight = v
+			}
+		case l.Key == PriorityLabel:
+			if v, err := parseUint16(l.Value); err == nil {
+				lt.Priority = v
+			}
+		case l.Key == SuppressLabel:
+			lt.Suppressed = l.Value == "true"
+		case strings.HasPrefix(l.Key, AliasLabelPrefix):
+			n, err := strconv.Atoi(strings.TrimPrefix(l.Key, AliasLabelPrefix))
+			if err != nil {
+				n = len(aliases)
+			}
+			aliases = append(aliases, indexedAlias{n: n, value: l.Value})
+		}
+	}
+	if len(aliases) > 0 {
+		sort.SliceStable(aliases, func(i, j int) bool { return aliases[i].n < aliases[j].n })
+		lt.Aliases = make([]string, len(aliases))
+		for i, a := range aliases {
+			lt.Aliases[i] = a.value
+		}
+	}
+
+	return lt
+}
+
+// indexedAlias pairs an alias value with the numeric suffix of the
+// mesos-dns.alias.<n> label it came from, so aliases can be sorted back
+// into the order their suffixes imply regardless of label arrival order.
+type indexedAlias struct {
+	n     int
+	value string
+}
+
+// parseUint16 parses a label value as an SRV weight/priority, which are
+// 16-bit fields per RFC 2782.
+func parseUint16(s string) (uint16, error) {
+	v, err := strconv.ParseUint(s, 10, 16)
+	return uint16(v), err
+}
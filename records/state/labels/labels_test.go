@@ -0,0 +1,79 @@
+package labels
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mesosphere/mesos-dns/records/state"
+)
+
+func taskWithLabels(labels ...state.Label) state.Task {
+	t := state.Task{
+		DiscoveryInfo: state.DiscoveryInfo{Name: "web"},
+	}
+	t.DiscoveryInfo.Labels.Labels = labels
+	return t
+}
+
+func TestNewTaskDefaults(t *testing.T) {
+	lt := NewTask(taskWithLabels())
+	if lt.Weight != DefaultWeight {
+		t.Errorf("Weight = %d, want %d", lt.Weight, DefaultWeight)
+	}
+	if lt.Priority != DefaultPriority {
+		t.Errorf("Priority = %d, want %d", lt.Priority, DefaultPriority)
+	}
+	if lt.Suppressed {
+		t.Error("Suppressed = true, want false")
+	}
+	if lt.Aliases != nil {
+		t.Errorf("Aliases = %v, want nil", lt.Aliases)
+	}
+}
+
+func TestNewTaskParsesLabels(t *testing.T) {
+	lt := NewTask(taskWithLabels(
+		state.Label{Key: WeightLabel, Value: "5"},
+		state.Label{Key: PriorityLabel, Value: "10"},
+		state.Label{Key: SuppressLabel, Value: "true"},
+	))
+	if lt.Weight != 5 {
+		t.Errorf("Weight = %d, want 5", lt.Weight)
+	}
+	if lt.Priority != 10 {
+		t.Errorf("Priority = %d, want 10", lt.Priority)
+	}
+	if !lt.Suppressed {
+		t.Error("Suppressed = false, want true")
+	}
+}
+
+func TestNewTaskInvalidWeightKeepsDefault(t *testing.T) {
+	lt := NewTask(taskWithLabels(state.Label{Key: WeightLabel, Value: "not-a-number"}))
+	if lt.Weight != DefaultWeight {
+		t.Errorf("Weight = %d, want default %d", lt.Weight, DefaultWeight)
+	}
+}
+
+func TestNewTaskOrdersAliasesBySuffix(t *testing.T) {
+	lt := NewTask(taskWithLabels(
+		state.Label{Key: AliasLabelPrefix + "2", Value: "second"},
+		state.Label{Key: AliasLabelPrefix + "0", Value: "first"},
+		state.Label{Key: AliasLabelPrefix + "1", Value: "middle"},
+	))
+	want := []string{"first", "middle", "second"}
+	if !reflect.DeepEqual(lt.Aliases, want) {
+		t.Errorf("Aliases = %v, want %v", lt.Aliases, want)
+	}
+}
+
+func TestNewTaskUnparsableAliasSuffixFallsBackToArrivalOrder(t *testing.T) {
+	lt := NewTask(taskWithLabels(
+		state.Label{Key: AliasLabelPrefix + "1", Value: "first"},
+		state.Label{Key: AliasLabelPrefix + "bogus", Value: "second"},
+	))
+	want := []string{"first", "second"}
+	if !reflect.DeepEqual(lt.Aliases, want) {
+		t.Errorf("Aliases = %v, want %v", lt.Aliases, want)
+	}
+}
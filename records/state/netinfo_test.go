@@ -0,0 +1,53 @@
+package state
+
+import "testing"
+
+func taskWithNetworks(networks ...NetworkInfo) Task {
+	return Task{
+		Statuses: []Status{
+			{
+				State:           "TASK_RUNNING",
+				Timestamp:       1,
+				ContainerStatus: ContainerStatus{NetworkInfos: networks},
+			},
+		},
+	}
+}
+
+func TestIPsNetinfoByName(t *testing.T) {
+	task := taskWithNetworks(
+		NetworkInfo{Name: "dcos", IPAddresses: []IPAddress{{IPAddress: "10.0.0.1"}}},
+		NetworkInfo{Name: "calico", IPAddresses: []IPAddress{{IPAddress: "10.0.0.2"}}},
+		NetworkInfo{IPAddresses: []IPAddress{{IPAddress: "10.0.0.3"}}},
+	)
+
+	cases := []struct {
+		src  string
+		want []string
+	}{
+		{"netinfo:dcos", []string{"10.0.0.1"}},
+		{"netinfo:calico", []string{"10.0.0.2"}},
+		{"netinfo:other", nil},
+	}
+
+	for _, c := range cases {
+		ips := task.IPs(c.src)
+		if len(ips) != len(c.want) {
+			t.Fatalf("IPs(%q) = %v, want %v", c.src, ips, c.want)
+		}
+		for i, ip := range ips {
+			if ip.String() != c.want[i] {
+				t.Errorf("IPs(%q)[%d] = %s, want %s", c.src, i, ip, c.want[i])
+			}
+		}
+	}
+}
+
+func TestIPsNetinfoUnnamedUnaffected(t *testing.T) {
+	task := taskWithNetworks(NetworkInfo{IPAddresses: []IPAddress{{IPAddress: "10.0.0.3"}}})
+
+	ips := task.IPs("netinfo")
+	if len(ips) != 1 || ips[0].String() != "10.0.0.3" {
+		t.Fatalf("IPs(\"netinfo\") = %v, want [10.0.0.3]", ips)
+	}
+}
@@ -73,6 +73,9 @@ type ContainerStatus struct {
 type NetworkInfo struct {
 	IPAddresses  []IPAddress   `json:"ip_addresses,omitempty"`
 	PortMappings []PortMapping `json:"port_mappings,omitempty"`
+	// Name is the CNI network this interface is attached to, e.g. "dcos" or
+	// "calico". It is empty for interfaces that aren't CNI-attached.
+	Name string `json:"name,omitempty"`
 	// back-compat with 0.25 IPAddress format
 	IPAddress string `json:"ip_address,omitempty"`
 }
@@ -118,6 +121,75 @@ func (t *Task) IP(srcs ...string) string {
 	return ""
 }
 
+// IP family filters accepted by Task.IPsFiltered.
+const (
+	// IPFilterIPv4 keeps only IPv4 addresses.
+	IPFilterIPv4 = "ipv4"
+	// IPFilterIPv6 keeps only IPv6 addresses.
+	IPFilterIPv6 = "ipv6"
+	// IPFilterPreferIPv4 keeps all addresses but sorts IPv4 first.
+	IPFilterPreferIPv4 = "prefer-ipv4"
+	// IPFilterPreferIPv6 keeps all addresses but sorts IPv6 first.
+	IPFilterPreferIPv6 = "prefer-ipv6"
+)
+
+// IPFiltered returns the first Task IP found in the given sources that
+// satisfies family, one of the IPFilter* constants.
+func (t *Task) IPFiltered(family string, srcs ...string) string {
+	if ips := t.IPsFiltered(family, srcs...); len(ips) > 0 {
+		return ips[0].String()
+	}
+	return ""
+}
+
+// IPsFiltered is like IPs, but additionally restricts or orders the result
+// by IP family according to family, one of the IPFilter* constants. An
+// unrecognized family is treated like no filter at all.
+func (t *Task) IPsFiltered(family string, srcs ...string) []net.IP {
+	ips := t.IPs(srcs...)
+	switch family {
+	case IPFilterIPv4:
+		return filterIPs(ips, isIPv4)
+	case IPFilterIPv6:
+		return filterIPs(ips, isIPv6)
+	case IPFilterPreferIPv4:
+		return preferIPs(ips, isIPv4)
+	case IPFilterPreferIPv6:
+		return preferIPs(ips, isIPv6)
+	default:
+		return ips
+	}
+}
+
+func isIPv4(ip net.IP) bool { return ip.To4() != nil }
+func isIPv6(ip net.IP) bool { return !isIPv4(ip) }
+
+// filterIPs returns only the IPs in ips for which keep returns true.
+func filterIPs(ips []net.IP, keep func(net.IP) bool) []net.IP {
+	filtered := make([]net.IP, 0, len(ips))
+	for _, ip := range ips {
+		if keep(ip) {
+			filtered = append(filtered, ip)
+		}
+	}
+	return filtered
+}
+
+// preferIPs stably reorders ips so that those matching first come before
+// those that don't, without dropping either.
+func preferIPs(ips []net.IP, first func(net.IP) bool) []net.IP {
+	preferred := make([]net.IP, 0, len(ips))
+	rest := make([]net.IP, 0, len(ips))
+	for _, ip := range ips {
+		if first(ip) {
+			preferred = append(preferred, ip)
+		} else {
+			rest = append(rest, ip)
+		}
+	}
+	return append(preferred, rest...)
+}
+
 // IPs returns a slice of IPs sourced from the given sources with ascending
 // priority.
 func (t *Task) IPs(srcs ...string) (ips []net.IP) {
@@ -125,17 +197,37 @@ func (t *Task) IPs(srcs ...string) (ips []net.IP) {
 		return nil
 	}
 	for i := range srcs {
-		if src, ok := sources[srcs[i]]; ok {
-			for _, srcIP := range src(t) {
-				if ip := net.ParseIP(srcIP); len(ip) > 0 {
-					ips = append(ips, ip)
-				}
+		src := resolveSource(srcs[i])
+		if src == nil {
+			continue
+		}
+		for _, srcIP := range src(t) {
+			if ip := net.ParseIP(srcIP); len(ip) > 0 {
+				ips = append(ips, ip)
 			}
 		}
 	}
 	return ips
 }
 
+// networkInfoSourcePrefix identifies IP sources of the form
+// "netinfo:<network-name>", which are resolved dynamically rather than
+// registered in the sources map.
+const networkInfoSourcePrefix = "netinfo:"
+
+// resolveSource looks up src in the sources map, falling back to a
+// dynamically built per-network source for "netinfo:<network-name>".
+func resolveSource(src string) func(*Task) []string {
+	if fn, ok := sources[src]; ok {
+		return fn
+	}
+	if strings.HasPrefix(src, networkInfoSourcePrefix) {
+		name := strings.TrimPrefix(src, networkInfoSourcePrefix)
+		return networkInfoIPsByName(name)
+	}
+	return nil
+}
+
 // sources maps the string representation of IP sources to their functions.
 var sources = map[string]func(*Task) []string{
 	"host":    hostIPs,
@@ -170,6 +262,27 @@ func networkInfoIPs(t *Task) []string {
 	})
 }
 
+// networkInfoIPsByName returns an IP source like networkInfoIPs that only
+// considers NetworkInfo entries whose Name matches the given CNI network
+// name. It reads only the IPAddresses list; the legacy single IPAddress
+// field is not consulted.
+func networkInfoIPsByName(name string) func(*Task) []string {
+	return func(t *Task) []string {
+		return statusIPs(t.Statuses, func(s *Status) []string {
+			var ips []string
+			for _, netinfo := range s.ContainerStatus.NetworkInfos {
+				if netinfo.Name != name {
+					continue
+				}
+				for _, ipAddress := range netinfo.IPAddresses {
+					ips = append(ips, ipAddress.IPAddress)
+				}
+			}
+			return ips
+		})
+	}
+}
+
 const (
 	// DockerIPLabel is the key of the Label which holds the Docker containerizer IP value.
 	DockerIPLabel = "Docker.NetworkSettings.IPAddress"
@@ -238,6 +351,7 @@ func labels(key string) func(*Status) []string {
 
 // Framework holds a framework as defined in the /state Mesos HTTP endpoint.
 type Framework struct {
+	ID       string `json:"id"`
 	Tasks    []Task `json:"tasks"`
 	PID      PID    `json:"pid"`
 	Name     string `json:"name"`
@@ -0,0 +1,412 @@
+// Package stream maintains an in-memory records/state.State by subscribing
+// to the Mesos master's Operator API v1 SUBSCRIBE call instead of polling
+// /state on every refresh. It falls back to polling when the master doesn't
+// speak the v1 API.
+package stream
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/mesosphere/mesos-dns/logging"
+	"github.com/mesosphere/mesos-dns/records/state"
+)
+
+// minBackoff and maxBackoff bound the reconnect backoff used between failed
+// SUBSCRIBE attempts; the actual delay is jitter(backoff), so it can be as
+// short as half of minBackoff.
+const (
+	minBackoff = 500 * time.Millisecond
+	maxBackoff = 30 * time.Second
+	pollPeriod = 10 * time.Second
+
+	// maxFrameSize bounds the recordio length prefix so a malformed or
+	// malicious frame length can't trigger an unbounded allocation.
+	maxFrameSize = 64 * 1024 * 1024
+)
+
+// call is the subset of the v1 master Call message we ever send.
+type call struct {
+	Type string `json:"type"`
+}
+
+// event is the subset of the v1 master Event message we know how to apply.
+type event struct {
+	Type             string           `json:"type"`
+	Subscribed       *subscribedData  `json:"subscribed,omitempty"`
+	TaskAdded        *taskData        `json:"task_added,omitempty"`
+	TaskUpdated      *taskData        `json:"task_updated,omitempty"`
+	AgentAdded       *agentData       `json:"agent_added,omitempty"`
+	AgentRemoved     *agentIDData     `json:"agent_removed,omitempty"`
+	FrameworkAdded   *frameworkData   `json:"framework_added,omitempty"`
+	FrameworkUpdated *frameworkData   `json:"framework_updated,omitempty"`
+	FrameworkRemoved *frameworkIDData `json:"framework_removed,omitempty"`
+}
+
+type subscribedData struct {
+	GetState state.State `json:"get_state"`
+}
+
+type taskData struct {
+	FrameworkID string     `json:"framework_id"`
+	Task        state.Task `json:"task"`
+}
+
+type agentData struct {
+	Agent state.Slave `json:"agent"`
+}
+
+type agentIDData struct {
+	AgentID struct {
+		Value string `json:"value"`
+	} `json:"agent_id"`
+}
+
+type frameworkData struct {
+	Framework state.Framework `json:"framework"`
+}
+
+type frameworkIDData struct {
+	FrameworkID struct {
+		Value string `json:"value"`
+	} `json:"framework_id"`
+}
+
+// Subscribe maintains an in-memory state.State fed by masterURL's Operator
+// API v1 SUBSCRIBE call and emits a fresh copy on the returned channel every
+// time an event is applied. If masterURL responds 404 or 406 to SUBSCRIBE
+// (pre-1.0 Mesos), Subscribe transparently falls back to polling masterURL's
+// /state endpoint every pollPeriod instead.
+//
+// The returned channel is closed when ctx is canceled.
+func Subscribe(ctx context.Context, masterURL string) (<-chan state.State, error) {
+	out := make(chan state.State)
+	go run(ctx, masterURL, out)
+	return out, nil
+}
+
+func run(ctx context.Context, masterURL string, out chan<- state.State) {
+	defer close(out)
+
+	backoff := minBackoff
+	for {
+		err := subscribeOnce(ctx, masterURL, out)
+		if err == errUnsupported {
+			logging.VeryVerbose.Println("stream: master does not support Operator API v1, falling back to polling")
+			poll(ctx, masterURL, out)
+			return
+		}
+		if err != nil {
+			logging.Error.Printf("stream: subscribe failed: %v", err)
+		} else {
+			backoff = minBackoff
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(jitter(backoff)):
+		}
+
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+// errUnsupported is returned by subscribeOnce when the master answers
+// SUBSCRIBE with 404 or 406, meaning it predates the v1 Operator API.
+var errUnsupported = fmt.Errorf("operator API v1 not supported by master")
+
+// subscribeOnce issues a single SUBSCRIBE call and applies events from the
+// response until it fails or ctx is canceled. On success it resets the
+// caller's backoff by simply returning nil.
+func subscribeOnce(ctx context.Context, masterURL string, out chan<- state.State) error {
+	body, err := json.Marshal(call{Type: "SUBSCRIBE"})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", masterURL+"/api/v1", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		// fall through to event processing below
+	case http.StatusTemporaryRedirect:
+		// leader redirect: the client already followed it transparently via
+		// http.DefaultClient, so reaching here with a 307 is unexpected;
+		// treat it defensively in case redirects were disabled.
+		return fmt.Errorf("stream: unexpected redirect to %s", resp.Header.Get("Location"))
+	case http.StatusNotFound, http.StatusNotAcceptable:
+		return errUnsupported
+	default:
+		return fmt.Errorf("stream: SUBSCRIBE returned %s", resp.Status)
+	}
+
+	var current state.State
+	r := newRecordIOReader(resp.Body)
+	for {
+		frame, err := r.ReadFrame()
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+
+		var ev event
+		if err := json.Unmarshal(frame, &ev); err != nil {
+			logging.Error.Printf("stream: dropping malformed event: %v", err)
+			continue
+		}
+
+		apply(&current, &ev)
+
+		select {
+		case out <- cloneState(current):
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// cloneState returns a copy of st whose Frameworks, per-framework Tasks, and
+// Slaves slices don't share backing arrays with st. apply mutates those
+// slices in place on the next event, so a value sent to a consumer must not
+// alias them or an already-emitted snapshot would change after the fact.
+func cloneState(st state.State) state.State {
+	clone := st
+	if st.Frameworks != nil {
+		clone.Frameworks = make([]state.Framework, len(st.Frameworks))
+		for i, f := range st.Frameworks {
+			clone.Frameworks[i] = f
+			if f.Tasks != nil {
+				clone.Frameworks[i].Tasks = append([]state.Task(nil), f.Tasks...)
+			}
+		}
+	}
+	if st.Slaves != nil {
+		clone.Slaves = append([]state.Slave(nil), st.Slaves...)
+	}
+	return clone
+}
+
+// apply mutates st in place according to ev.
+func apply(st *state.State, ev *event) {
+	switch ev.Type {
+	case "SUBSCRIBED":
+		if ev.Subscribed != nil {
+			*st = ev.Subscribed.GetState
+		}
+	case "TASK_ADDED":
+		if ev.TaskAdded != nil {
+			putTask(st, ev.TaskAdded.FrameworkID, ev.TaskAdded.Task)
+		}
+	case "TASK_UPDATED":
+		if ev.TaskUpdated != nil {
+			putTask(st, ev.TaskUpdated.FrameworkID, ev.TaskUpdated.Task)
+		}
+	case "AGENT_ADDED":
+		if ev.AgentAdded != nil {
+			putAgent(st, ev.AgentAdded.Agent)
+		}
+	case "AGENT_REMOVED":
+		if ev.AgentRemoved != nil {
+			removeAgent(st, ev.AgentRemoved.AgentID.Value)
+		}
+	case "FRAMEWORK_ADDED":
+		if ev.FrameworkAdded != nil {
+			putFramework(st, ev.FrameworkAdded.Framework)
+		}
+	case "FRAMEWORK_UPDATED":
+		if ev.FrameworkUpdated != nil {
+			putFramework(st, ev.FrameworkUpdated.Framework)
+		}
+	case "FRAMEWORK_REMOVED":
+		if ev.FrameworkRemoved != nil {
+			removeFramework(st, ev.FrameworkRemoved.FrameworkID.Value)
+		}
+	}
+}
+
+func putTask(st *state.State, frameworkID string, t state.Task) {
+	for i := range st.Frameworks {
+		for j := range st.Frameworks[i].Tasks {
+			if st.Frameworks[i].Tasks[j].ID == t.ID {
+				st.Frameworks[i].Tasks[j] = t
+				return
+			}
+		}
+	}
+	for i := range st.Frameworks {
+		if st.Frameworks[i].ID == frameworkID {
+			st.Frameworks[i].Tasks = append(st.Frameworks[i].Tasks, t)
+			return
+		}
+	}
+	// The framework itself hasn't been announced yet (FRAMEWORK_ADDED may
+	// arrive after its tasks); hold the task under a placeholder framework
+	// so it isn't silently dropped, to be merged in by a later putFramework.
+	st.Frameworks = append(st.Frameworks, state.Framework{
+		ID:    frameworkID,
+		Tasks: []state.Task{t},
+	})
+}
+
+func putAgent(st *state.State, a state.Slave) {
+	for i := range st.Slaves {
+		if st.Slaves[i].ID == a.ID {
+			st.Slaves[i] = a
+			return
+		}
+	}
+	st.Slaves = append(st.Slaves, a)
+}
+
+func removeAgent(st *state.State, id string) {
+	slaves := st.Slaves[:0]
+	for _, s := range st.Slaves {
+		if s.ID != id {
+			slaves = append(slaves, s)
+		}
+	}
+	st.Slaves = slaves
+}
+
+func putFramework(st *state.State, f state.Framework) {
+	for i := range st.Frameworks {
+		if st.Frameworks[i].ID == f.ID {
+			// FRAMEWORK_ADDED/UPDATED events don't carry a task list; keep
+			// whatever tasks putTask has already attached to this framework.
+			if len(f.Tasks) == 0 {
+				f.Tasks = st.Frameworks[i].Tasks
+			}
+			st.Frameworks[i] = f
+			return
+		}
+	}
+	st.Frameworks = append(st.Frameworks, f)
+}
+
+func removeFramework(st *state.State, id string) {
+	frameworks := st.Frameworks[:0]
+	for _, f := range st.Frameworks {
+		if f.ID == id {
+			continue
+		}
+		frameworks = append(frameworks, f)
+	}
+	st.Frameworks = frameworks
+}
+
+// poll is the pre-v1 fallback: it re-fetches masterURL's /state endpoint
+// every pollPeriod and pushes the decoded result to out.
+func poll(ctx context.Context, masterURL string, out chan<- state.State) {
+	ticker := time.NewTicker(pollPeriod)
+	defer ticker.Stop()
+
+	for {
+		st, err := fetchState(ctx, masterURL)
+		if err != nil {
+			logging.Error.Printf("stream: poll failed: %v", err)
+		} else {
+			select {
+			case out <- st:
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func fetchState(ctx context.Context, masterURL string) (state.State, error) {
+	var st state.State
+
+	req, err := http.NewRequest("GET", masterURL+"/state", nil)
+	if err != nil {
+		return st, err
+	}
+	req = req.WithContext(ctx)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return st, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return st, fmt.Errorf("stream: GET /state returned %s", resp.Status)
+	}
+
+	err = json.NewDecoder(resp.Body).Decode(&st)
+	return st, err
+}
+
+// jitter returns a random duration in [d/2, d), so that many clients
+// reconnecting to the same master don't do so in lockstep.
+func jitter(d time.Duration) time.Duration {
+	delta := time.Duration(rand.Int63n(int64(d)))
+	return d/2 + delta/2
+}
+
+// recordIOReader reads Mesos's recordio framing: each frame is preceded by
+// its length in bytes as an ASCII decimal string terminated by '\n'.
+type recordIOReader struct {
+	r *bufio.Reader
+}
+
+func newRecordIOReader(r io.Reader) *recordIOReader {
+	return &recordIOReader{r: bufio.NewReader(r)}
+}
+
+func (rr *recordIOReader) ReadFrame() ([]byte, error) {
+	line, err := rr.r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+
+	n, err := strconv.Atoi(bytesTrimNewline(line))
+	if err != nil {
+		return nil, fmt.Errorf("stream: invalid recordio length %q: %v", line, err)
+	}
+	if n < 0 || n > maxFrameSize {
+		return nil, fmt.Errorf("stream: recordio frame length %d exceeds maximum %d", n, maxFrameSize)
+	}
+
+	frame := make([]byte, n)
+	if _, err := io.ReadFull(rr.r, frame); err != nil {
+		return nil, err
+	}
+	return frame, nil
+}
+
+func bytesTrimNewline(s string) string {
+	return string(bytes.TrimRight([]byte(s), "\r\n"))
+}
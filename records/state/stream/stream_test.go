@@ -0,0 +1,128 @@
+package stream
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mesosphere/mesos-dns/records/state"
+)
+
+func TestCloneStateUnaffectedByLaterMutation(t *testing.T) {
+	st := state.State{
+		Frameworks: []state.Framework{
+			{ID: "fwA", Tasks: []state.Task{{ID: "task.1", State: "TASK_STAGING"}}},
+		},
+		Slaves: []state.Slave{{ID: "slave.1"}},
+	}
+
+	snapshot := cloneState(st)
+
+	putTask(&st, "fwA", state.Task{ID: "task.1", State: "TASK_RUNNING"})
+
+	if got := snapshot.Frameworks[0].Tasks[0].State; got != "TASK_STAGING" {
+		t.Errorf("snapshot task State = %q after later mutation, want unchanged TASK_STAGING", got)
+	}
+	if got := st.Frameworks[0].Tasks[0].State; got != "TASK_RUNNING" {
+		t.Errorf("live state task State = %q, want TASK_RUNNING", got)
+	}
+}
+
+func TestPutTaskAttachesToOwningFramework(t *testing.T) {
+	st := &state.State{Frameworks: []state.Framework{
+		{ID: "fwA", Name: "marathon"},
+		{ID: "fwB", Name: "chronos"},
+	}}
+
+	putTask(st, "fwB", state.Task{ID: "task.1", FrameworkID: "fwB"})
+
+	if len(st.Frameworks[0].Tasks) != 0 {
+		t.Errorf("fwA got %d tasks, want 0", len(st.Frameworks[0].Tasks))
+	}
+	if len(st.Frameworks[1].Tasks) != 1 || st.Frameworks[1].Tasks[0].ID != "task.1" {
+		t.Errorf("fwB tasks = %v, want [task.1]", st.Frameworks[1].Tasks)
+	}
+}
+
+func TestPutTaskUpdatesExisting(t *testing.T) {
+	st := &state.State{Frameworks: []state.Framework{
+		{ID: "fwA", Tasks: []state.Task{{ID: "task.1", State: "TASK_STAGING"}}},
+	}}
+
+	putTask(st, "fwA", state.Task{ID: "task.1", State: "TASK_RUNNING"})
+
+	if len(st.Frameworks[0].Tasks) != 1 {
+		t.Fatalf("got %d tasks, want 1", len(st.Frameworks[0].Tasks))
+	}
+	if st.Frameworks[0].Tasks[0].State != "TASK_RUNNING" {
+		t.Errorf("State = %q, want TASK_RUNNING", st.Frameworks[0].Tasks[0].State)
+	}
+}
+
+func TestPutTaskCreatesPlaceholderFrameworkWhenUnknown(t *testing.T) {
+	st := &state.State{}
+
+	putTask(st, "fwX", state.Task{ID: "task.1"})
+
+	if len(st.Frameworks) != 1 || st.Frameworks[0].ID != "fwX" {
+		t.Fatalf("Frameworks = %v, want one framework with ID fwX", st.Frameworks)
+	}
+	if len(st.Frameworks[0].Tasks) != 1 || st.Frameworks[0].Tasks[0].ID != "task.1" {
+		t.Errorf("tasks = %v, want [task.1]", st.Frameworks[0].Tasks)
+	}
+}
+
+func TestPutFrameworkKeepsTasksAttachedByPlaceholder(t *testing.T) {
+	st := &state.State{Frameworks: []state.Framework{
+		{ID: "fwX", Tasks: []state.Task{{ID: "task.1"}}},
+	}}
+
+	putFramework(st, state.Framework{ID: "fwX", Name: "marathon"})
+
+	if len(st.Frameworks) != 1 {
+		t.Fatalf("Frameworks = %v, want 1 entry", st.Frameworks)
+	}
+	if st.Frameworks[0].Name != "marathon" {
+		t.Errorf("Name = %q, want marathon", st.Frameworks[0].Name)
+	}
+	if len(st.Frameworks[0].Tasks) != 1 || st.Frameworks[0].Tasks[0].ID != "task.1" {
+		t.Errorf("Tasks = %v, want [task.1]", st.Frameworks[0].Tasks)
+	}
+}
+
+func TestRemoveFrameworkCommitsFilteredSlice(t *testing.T) {
+	st := &state.State{Frameworks: []state.Framework{
+		{ID: "fwA"}, {ID: "fwB"}, {ID: "fwC"},
+	}}
+
+	removeFramework(st, "fwB")
+
+	if len(st.Frameworks) != 2 {
+		t.Fatalf("Frameworks = %v, want 2 entries", st.Frameworks)
+	}
+	for _, f := range st.Frameworks {
+		if f.ID == "fwB" {
+			t.Errorf("fwB still present in %v", st.Frameworks)
+		}
+	}
+}
+
+func TestReadFrameRejectsOversizedLength(t *testing.T) {
+	r := newRecordIOReader(strings.NewReader("999999999999\n"))
+
+	_, err := r.ReadFrame()
+	if err == nil {
+		t.Fatal("ReadFrame() err = nil, want error for oversized length")
+	}
+}
+
+func TestReadFrameReadsFrame(t *testing.T) {
+	r := newRecordIOReader(strings.NewReader("5\nhello"))
+
+	frame, err := r.ReadFrame()
+	if err != nil {
+		t.Fatalf("ReadFrame() err = %v", err)
+	}
+	if string(frame) != "hello" {
+		t.Errorf("frame = %q, want hello", frame)
+	}
+}